@@ -0,0 +1,100 @@
+package dnssniffer
+
+import (
+	"container/list"
+	"net"
+	"sync"
+	"time"
+)
+
+// defaultCacheSize bounds how many recent IP->domain attributions are kept
+// in memory, evicting the least recently used entry once exceeded.
+const defaultCacheSize = 4096
+
+// defaultMinTTL is used when a DNS response's TTL is zero or negative.
+const defaultMinTTL = 10 * time.Second
+
+type record struct {
+	key    string
+	domain string
+	pid    int
+	expiry time.Time
+}
+
+// recentDomainCache is a bounded LRU cache mapping an IP address to the
+// domain a DNS query resolved it to and the PID that issued the query.
+// Entries expire according to the DNS response's TTL, so a lookup for an IP
+// that was resolved long ago correctly misses even if it hasn't been
+// evicted yet.
+type recentDomainCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+func newRecentDomainCache(capacity int) *recentDomainCache {
+	return &recentDomainCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *recentDomainCache) add(ip net.IP, domain string, pid int, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = defaultMinTTL
+	}
+	key := ip.String()
+	expiry := time.Now().Add(ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		rec := el.Value.(*record) //nolint:forcetypeassert
+		rec.domain = domain
+		rec.pid = pid
+		rec.expiry = expiry
+		return
+	}
+
+	el := c.ll.PushFront(&record{key: key, domain: domain, pid: pid, expiry: expiry})
+	c.items[key] = el
+
+	if c.ll.Len() > c.capacity {
+		c.evictOldest()
+	}
+}
+
+func (c *recentDomainCache) evictOldest() {
+	el := c.ll.Back()
+	if el == nil {
+		return
+	}
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*record).key) //nolint:forcetypeassert
+}
+
+func (c *recentDomainCache) lookup(ip net.IP) (domain string, pid int, ok bool) {
+	key := ip.String()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.items[key]
+	if !found {
+		return "", 0, false
+	}
+
+	rec := el.Value.(*record) //nolint:forcetypeassert
+	if time.Now().After(rec.expiry) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return "", 0, false
+	}
+
+	c.ll.MoveToFront(el)
+	return rec.domain, rec.pid, true
+}
@@ -0,0 +1,46 @@
+// Package dnssniffer is intended to passively observe DNS responses on the
+// host and correlate them with the PID that issued the query, looked up via
+// the socket table at the moment the query was seen. This would let process
+// attribution fall back to "which process just resolved this domain" for
+// connections whose originating process has already exited by the time the
+// connection itself is inspected.
+//
+// STATUS: not implemented end-to-end, and not a working feature as shipped.
+// No platform packet capture backend exists, so Observe is never called and
+// LookupRecentDomain always misses; the package is intentionally not
+// registered as a modules.Module, since there is nothing for it to start or
+// stop. Its one consumer, process.GetUnidentifiedProcessForConnection, is
+// itself not called from anywhere either. This is tracked as a follow-up,
+// not a closed feature: landing it for real requires a capture backend
+// (AF_PACKET on Linux, a userspace filter on Windows/macOS), wiring Observe
+// to it, registering a module, and calling GetUnidentifiedProcessForConnection
+// from the per-connection process resolution path.
+package dnssniffer
+
+import (
+	"net"
+	"time"
+)
+
+var recentQueries = newRecentDomainCache(defaultCacheSize)
+
+// Observe records that domain was resolved to ip by the process with the
+// given pid, valid for ttl. It is meant to be called by a platform capture
+// backend as DNS responses are seen on the wire; no such backend exists yet.
+func Observe(ip net.IP, domain string, pid int, ttl int) {
+	recentQueries.add(ip, domain, pid, secondsToDuration(ttl))
+}
+
+// LookupRecentDomain returns the domain a DNS query recently resolved to ip,
+// and the PID of the process that issued that query, if the sniffer
+// observed it within the response's TTL.
+func LookupRecentDomain(ip net.IP) (domain string, pid int, ok bool) {
+	return recentQueries.lookup(ip)
+}
+
+func secondsToDuration(ttl int) time.Duration {
+	if ttl <= 0 {
+		return defaultMinTTL
+	}
+	return time.Duration(ttl) * time.Second
+}
@@ -0,0 +1,87 @@
+package customlists
+
+import (
+	"net/netip"
+	"strings"
+
+	"go4.org/netipx"
+)
+
+// Verdict is the result of a custom filter list lookup.
+type Verdict int8
+
+const (
+	// NoMatch means neither a block nor an allow rule matched.
+	NoMatch Verdict = iota
+	// Block means a block rule matched.
+	Block
+	// Allow means an allow rule matched. Allow rules take precedence over
+	// block rules, so callers can use a custom filter list to carve out
+	// exceptions from other block lists.
+	Allow
+)
+
+var (
+	// blockedIPSet and allowedIPSet are compiled from all configured sources'
+	// IP/CIDR entries on every rebuildFilterLists call. Using an IPSet instead
+	// of a map gives O(log n) lookups for millions of prefixes, and lets a
+	// single entry cover an entire range instead of one map key per address.
+	blockedIPSet *netipx.IPSet
+	allowedIPSet *netipx.IPSet
+
+	// domainsAllowFilterList holds domain allow-rules (prefixed with "!", or
+	// "@@" in adblock-plus sources), checked before domainsFilterList.
+	domainsAllowFilterList = make(map[string]struct{})
+)
+
+// rebuildFilterLists recomputes the shared lookup structures from the cached
+// parse results of all configured sources. Callers must hold filterListLock.
+func rebuildFilterLists() {
+	domainsFilterList = make(map[string]struct{})
+	domainsAllowFilterList = make(map[string]struct{})
+	autonomousSystemsFilterList = make(map[uint]struct{})
+	countryCodesFilterList = make(map[string]struct{})
+
+	var blockBuilder, allowBuilder netipx.IPSetBuilder
+
+	// Domains are keyed as fully qualified names with a trailing dot, the
+	// form that lookups (and splitDomain) use.
+	for _, source := range filterListSources {
+		for _, domain := range source.parsed.domains {
+			domainsFilterList[strings.TrimSuffix(domain, ".")+"."] = struct{}{}
+		}
+		for _, domain := range source.parsed.allowDomains {
+			domainsAllowFilterList[strings.TrimSuffix(domain, ".")+"."] = struct{}{}
+		}
+		for _, prefix := range source.parsed.ips {
+			blockBuilder.AddPrefix(prefix)
+		}
+		for _, prefix := range source.parsed.allowIPs {
+			allowBuilder.AddPrefix(prefix)
+		}
+		for _, asn := range source.parsed.asns {
+			autonomousSystemsFilterList[asn] = struct{}{}
+		}
+		for _, country := range source.parsed.countries {
+			countryCodesFilterList[country] = struct{}{}
+		}
+	}
+
+	if set, err := blockBuilder.IPSet(); err == nil {
+		blockedIPSet = set
+	}
+	if set, err := allowBuilder.IPSet(); err == nil {
+		allowedIPSet = set
+	}
+}
+
+// addrFromIPBytes converts a net.IP to a netip.Addr, unmapping IPv4-in-IPv6
+// addresses so that v4 and v4-mapped-v6 representations of the same address
+// match the same IPSet entries.
+func addrFromIPBytes(ip []byte) (netip.Addr, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
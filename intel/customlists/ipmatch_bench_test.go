@@ -0,0 +1,60 @@
+package customlists
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+
+	"go4.org/netipx"
+)
+
+// buildBenchmarkIPSet builds an IPSet of n disjoint /32 prefixes, to
+// approximate a large real-world CIDR block list. The i-th prefix is the
+// address with numeric value 2*i, so no two entries are adjacent and the
+// IPSet cannot merge them into fewer ranges.
+func buildBenchmarkIPSet(n int) *netipx.IPSet {
+	var builder netipx.IPSetBuilder
+
+	for i := 0; i < n; i++ {
+		v := uint32(i) * 2 //nolint:gosec
+		addr := netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
+		builder.Add(addr)
+	}
+
+	set, err := builder.IPSet()
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+// BenchmarkLookupIPVerdict measures LookupIPVerdict against a filter list
+// with millions of compiled prefixes, demonstrating that the underlying
+// netipx.IPSet gives sub-linear (binary-search) lookup instead of the O(n)
+// scan a flat map of ranges would require.
+func BenchmarkLookupIPVerdict(b *testing.B) {
+	for _, n := range []int{1_000, 100_000, 4_000_000} {
+		b.Run(fmt.Sprintf("prefixes=%d", n), func(b *testing.B) {
+			filterListLock.Lock()
+			blockedIPSet = buildBenchmarkIPSet(n)
+			allowedIPSet = nil
+			filterListLock.Unlock()
+
+			// Look up an address in the middle of the built range, and one
+			// guaranteed to miss.
+			mid := uint32(n/2) * 2                                                 //nolint:gosec
+			hit := net.IPv4(byte(mid>>24), byte(mid>>16), byte(mid>>8), byte(mid)) //nolint:gosec
+			miss := net.IPv4(255, 255, 255, 255)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if i%2 == 0 {
+					LookupIPVerdict(hit)
+				} else {
+					LookupIPVerdict(miss)
+				}
+			}
+		})
+	}
+}
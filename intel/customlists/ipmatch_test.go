@@ -0,0 +1,119 @@
+package customlists
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"go4.org/netipx"
+)
+
+func setFilterLists(t *testing.T, blocked, allowed *netipx.IPSet, domains, allowDomains map[string]struct{}) {
+	t.Helper()
+
+	filterListLock.Lock()
+	prevBlocked, prevAllowed := blockedIPSet, allowedIPSet
+	prevDomains, prevAllowDomains := domainsFilterList, domainsAllowFilterList
+	blockedIPSet, allowedIPSet = blocked, allowed
+	domainsFilterList, domainsAllowFilterList = domains, allowDomains
+	filterListLock.Unlock()
+
+	t.Cleanup(func() {
+		filterListLock.Lock()
+		blockedIPSet, allowedIPSet = prevBlocked, prevAllowed
+		domainsFilterList, domainsAllowFilterList = prevDomains, prevAllowDomains
+		filterListLock.Unlock()
+	})
+}
+
+func TestLookupIPVerdictPrecedence(t *testing.T) {
+	var blockBuilder, allowBuilder netipx.IPSetBuilder
+	blockBuilder.AddPrefix(netip.MustParsePrefix("10.0.0.0/24"))
+	allowBuilder.AddPrefix(netip.MustParsePrefix("10.0.0.128/25"))
+
+	blocked, err := blockBuilder.IPSet()
+	if err != nil {
+		t.Fatalf("blockBuilder.IPSet() error = %v", err)
+	}
+	allowed, err := allowBuilder.IPSet()
+	if err != nil {
+		t.Fatalf("allowBuilder.IPSet() error = %v", err)
+	}
+
+	setFilterLists(t, blocked, allowed, nil, nil)
+
+	tests := []struct {
+		name string
+		ip   net.IP
+		want Verdict
+	}{
+		{name: "blocked only", ip: net.ParseIP("10.0.0.1"), want: Block},
+		{name: "allowed takes precedence over overlapping block range", ip: net.ParseIP("10.0.0.200"), want: Allow},
+		{name: "no match", ip: net.ParseIP("192.168.1.1"), want: NoMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LookupIPVerdict(tt.ip); got != tt.want {
+				t.Errorf("LookupIPVerdict(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupIPDeprecatedBoolOnlyReportsBlock(t *testing.T) {
+	var allowBuilder netipx.IPSetBuilder
+	allowBuilder.AddPrefix(netip.MustParsePrefix("10.0.0.0/24"))
+	allowed, err := allowBuilder.IPSet()
+	if err != nil {
+		t.Fatalf("allowBuilder.IPSet() error = %v", err)
+	}
+
+	setFilterLists(t, nil, allowed, nil, nil)
+
+	if LookupIP(net.ParseIP("10.0.0.1")) {
+		t.Error("LookupIP() = true for an allow-listed IP, want false (bool API can't distinguish Allow from NoMatch)")
+	}
+}
+
+func TestLookupDomainVerdictPrecedence(t *testing.T) {
+	domains := map[string]struct{}{"blocked.example.": {}, "both.example.": {}}
+	allowDomains := map[string]struct{}{"allowed.example.": {}, "both.example.": {}}
+	setFilterLists(t, nil, nil, domains, allowDomains)
+
+	tests := []struct {
+		name       string
+		domain     string
+		filterSubs bool
+		wantVerdict Verdict
+		wantMatch  string
+	}{
+		{name: "blocked exact match", domain: "blocked.example.", filterSubs: false, wantVerdict: Block, wantMatch: "blocked.example."},
+		{name: "allowed exact match", domain: "allowed.example.", filterSubs: false, wantVerdict: Allow, wantMatch: "allowed.example."},
+		{name: "allow takes precedence over block for the same domain", domain: "both.example.", filterSubs: false, wantVerdict: Allow, wantMatch: "both.example."},
+		{name: "no match", domain: "other.example.", filterSubs: false, wantVerdict: NoMatch, wantMatch: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotVerdict, gotMatch := LookupDomainVerdict(tt.domain, tt.filterSubs)
+			if gotVerdict != tt.wantVerdict || gotMatch != tt.wantMatch {
+				t.Errorf("LookupDomainVerdict(%q, %v) = (%v, %q), want (%v, %q)",
+					tt.domain, tt.filterSubs, gotVerdict, gotMatch, tt.wantVerdict, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestLookupDomainDeprecatedBoolWrapsVerdict(t *testing.T) {
+	domains := map[string]struct{}{"blocked.example.": {}}
+	allowDomains := map[string]struct{}{"allowed.example.": {}}
+	setFilterLists(t, nil, nil, domains, allowDomains)
+
+	if blocked, _ := LookupDomain("blocked.example.", false); !blocked {
+		t.Error("LookupDomain() = false for a block-listed domain, want true")
+	}
+	if blocked, _ := LookupDomain("allowed.example.", false); blocked {
+		t.Error("LookupDomain() = true for an allow-listed domain, want false (bool API can't distinguish Allow from NoMatch)")
+	}
+}
@@ -2,8 +2,8 @@ package customlists
 
 import (
 	"context"
+	"errors"
 	"net"
-	"os"
 	"regexp"
 	"strings"
 	"sync"
@@ -13,6 +13,8 @@ import (
 
 	"github.com/safing/portbase/api"
 	"github.com/safing/portbase/modules"
+
+	"github.com/safing/portmaster/logging"
 )
 
 var module *modules.Module
@@ -29,11 +31,15 @@ var (
 )
 
 var (
-	filterListFilePath         string
-	filterListFileModifiedTime time.Time
+	// filterListSources holds all configured filter list sources, local and
+	// remote. It is rebuilt from the current config on every update check so
+	// that added/removed sources take effect without a restart.
+	filterListSources []*FilterListSource
 
 	filterListLock sync.RWMutex
 	parserTask     *modules.Task
+
+	logger = logging.New("intel/customlists")
 )
 
 func init() {
@@ -49,6 +55,14 @@ func prep() error {
 		return err
 	}
 
+	// Register the config for multi-source and retry support.
+	if err := registerSourceConfig(); err != nil {
+		return err
+	}
+	if err := registerRetryConfig(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -87,68 +101,228 @@ func start() error {
 		return err
 	}
 
+	// Register api endpoint for inspecting per-source filter list status.
+	if err := api.RegisterEndpoint(api.Endpoint{
+		Path:      "customlists/status",
+		Read:      api.PermitUser,
+		BelongsTo: module,
+		StructFunc: func(ar *api.Request) (i interface{}, err error) {
+			filterListLock.RLock()
+			defer filterListLock.RUnlock()
+
+			return getSourcesStatus(), nil
+		},
+		Name:        "Get custom filter list status",
+		Description: "Returns per-source entry counts and last errors for the configured custom filter lists.",
+	}); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func checkAndUpdateFilterList() {
-	filterListLock.Lock()
-	defer filterListLock.Unlock()
+// syncConfiguredSources rebuilds filterListSources from the current config,
+// carrying over cached state (modtime, ETag, last result) for sources that
+// are still configured. Callers must hold filterListLock.
+func syncConfiguredSources() {
+	filterListSources = mergeSources(loadConfiguredSources(), filterListSources)
+}
 
-	// Get path and ignore if empty
-	filePath := getFilePath()
-	if filePath == "" {
-		return
+// mergeSources matches each freshly-loaded configured source against
+// existing by Path+URL and carries over its cached reload state (modtime,
+// ETag, last parse result), so that a source whose config is unchanged
+// doesn't spuriously refetch or lose its entries on the next check. A
+// source no longer present in configured is dropped; one that wasn't in
+// existing starts with zero state, as loadConfiguredSources built it.
+func mergeSources(configured, existing []*FilterListSource) []*FilterListSource {
+	merged := make([]*FilterListSource, 0, len(configured))
+
+	for _, source := range configured {
+		for _, existingSource := range existing {
+			if existingSource.Path == source.Path && existingSource.URL == source.URL {
+				source.modifiedTime = existingSource.modifiedTime
+				source.etag = existingSource.etag
+				source.lastModified = existingSource.lastModified
+				source.parsed = existingSource.parsed
+				source.entries = existingSource.entries
+				source.retryAttempts = existingSource.retryAttempts
+				source.lastErr = existingSource.lastErr
+				source.lastUpdate = existingSource.lastUpdate
+				break
+			}
+		}
+		merged = append(merged, source)
 	}
 
-	// Schedule next update check
+	return merged
+}
+
+func checkAndUpdateFilterList() {
+	// Schedule next update check.
 	parserTask.Schedule(time.Now().Add(1 * time.Minute))
 
-	// Try to get file info
-	modifiedTime := time.Now()
-	if fileInfo, err := os.Stat(filePath); err == nil {
-		modifiedTime = fileInfo.ModTime()
+	// Pick up added/removed sources since the last check, and grab a
+	// snapshot to reload. This is the only part that needs the write lock:
+	// the actual fetch/parse/retry below must NOT hold filterListLock, since
+	// it can block for the length of the whole retry window (a slow or
+	// hanging remote source) and filterListLock is also taken by
+	// LookupIP/LookupDomain/LookupASN/LookupCountry on the live firewall
+	// verdict path.
+	filterListLock.Lock()
+	syncConfiguredSources()
+	sourcesSnapshot := append([]*FilterListSource(nil), filterListSources...)
+	filterListLock.Unlock()
+
+	// Reload each source independently, based on its own modtime/ETag,
+	// retrying transient failures with backoff before giving up and falling
+	// back to the previously-loaded version.
+	rebuild := false
+	for _, source := range sourcesSnapshot {
+		var (
+			parsed   sourceEntries
+			reloaded bool
+		)
+
+		start := time.Now()
+		attempts, err := retry(currentRetryConfig(), func() error {
+			content, changed, fetchErr := source.fetch()
+			if fetchErr != nil {
+				return fetchErr
+			}
+			if !changed {
+				reloaded = false
+				return nil
+			}
+			defer content.Close() //nolint:errcheck
+
+			result, parseErr := parseSourceContent(content, source.Format)
+			if parseErr != nil {
+				// Drop the change marker that fetch just stored, so the next
+				// attempt re-reads the content instead of seeing it unchanged.
+				source.modifiedTime = time.Time{}
+				source.etag = ""
+				source.lastModified = ""
+				return parseErr
+			}
+			parsed = result
+			reloaded = true
+			return nil
+		})
+
+		// Only the bookkeeping on the source itself, and any shared state it
+		// feeds, needs the lock - never the fetch/parse/retry above.
+		filterListLock.Lock()
+		source.retryAttempts = attempts
+		switch {
+		case err != nil:
+			source.lastErr = err
+			fields := []logging.Field{
+				logging.F("path", source.String()),
+				logging.F("attempts", attempts),
+				logging.F("err", err),
+			}
+			var le *lineError
+			if errors.As(err, &le) {
+				fields = append(fields, logging.F("line", le.line))
+			}
+			logger.Error("filter_list_parse_failed", fields...)
+		case reloaded:
+			source.parsed = parsed
+			source.entries = parsed.count()
+			source.lastErr = nil
+			source.lastUpdate = time.Now()
+			rebuild = true
+
+			logger.Info("filter_list_reloaded",
+				logging.F("path", source.String()),
+				logging.F("entries", source.entries),
+				logging.F("attempts", attempts),
+				logging.F("took_ms", time.Since(start).Milliseconds()),
+			)
+		}
+		filterListLock.Unlock()
 	}
 
-	// Check if file path has changed or if modified time has changed
-	if filterListFilePath != filePath || !filterListFileModifiedTime.Equal(modifiedTime) {
-		err := parseFile(filePath)
-		if err != nil {
-			return
-		}
-		filterListFileModifiedTime = modifiedTime
-		filterListFilePath = filePath
+	if !rebuild {
+		return
 	}
+
+	// Recompute the shared lookup structures from all sources' cached parse
+	// results, so that reloading one source can't lose entries contributed by
+	// another that didn't change this round.
+	filterListLock.Lock()
+	defer filterListLock.Unlock()
+	rebuildFilterLists()
 }
 
 // LookupIP checks if the IP address is in a custom filter list.
+//
+// Deprecated: LookupIP collapses the tri-state result of LookupIPVerdict into
+// a bool, so a matching allow rule is indistinguishable from no match at all.
+// Existing callers keep working against block-only lists; use
+// LookupIPVerdict for allowlisting.
 func LookupIP(ip net.IP) bool {
+	return LookupIPVerdict(ip) == Block
+}
+
+// LookupIPVerdict checks if the IP address is in a custom filter list, or
+// covered by a CIDR range in one, returning a tri-state Verdict. Allow rules
+// take precedence over block rules, so callers can use Allow to implement
+// explicit allowlisting on top of other block lists.
+func LookupIPVerdict(ip net.IP) Verdict {
 	filterListLock.RLock()
 	defer filterListLock.RUnlock()
 
-	_, ok := ipAddressesFilterList[ip.String()]
-	return ok
+	addr, ok := addrFromIPBytes(ip)
+	if !ok {
+		return NoMatch
+	}
+
+	if allowedIPSet != nil && allowedIPSet.Contains(addr) {
+		return Allow
+	}
+	if blockedIPSet != nil && blockedIPSet.Contains(addr) {
+		return Block
+	}
+	return NoMatch
 }
 
 // LookupDomain checks if the Domain is in a custom filter list.
+//
+// Deprecated: LookupDomain collapses the tri-state result of
+// LookupDomainVerdict into a bool, so a matching allow rule is
+// indistinguishable from no match at all. Existing callers keep working
+// against block-only lists; use LookupDomainVerdict for allowlisting.
 func LookupDomain(fullDomain string, filterSubdomains bool) (bool, string) {
+	verdict, domain := LookupDomainVerdict(fullDomain, filterSubdomains)
+	return verdict == Block, domain
+}
+
+// LookupDomainVerdict checks if the Domain is in a custom filter list,
+// returning a tri-state Verdict. Allow rules take precedence over block
+// rules.
+func LookupDomainVerdict(fullDomain string, filterSubdomains bool) (Verdict, string) {
 	filterListLock.RLock()
 	defer filterListLock.RUnlock()
 
+	domainsToCheck := []string{fullDomain}
 	if filterSubdomains {
-		// Check if domain is in the list and all its subdomains.
-		listOfDomains := splitDomain(fullDomain)
-		for _, domain := range listOfDomains {
-			_, ok := domainsFilterList[domain]
-			if ok {
-				return true, domain
-			}
+		// Check the domain and all its parent domains up to the public suffix.
+		domainsToCheck = splitDomain(fullDomain)
+	}
+
+	for _, domain := range domainsToCheck {
+		if _, ok := domainsAllowFilterList[domain]; ok {
+			return Allow, domain
 		}
-	} else {
-		// Check only if the domain is in the list
-		_, ok := domainsFilterList[fullDomain]
-		return ok, fullDomain
 	}
-	return false, ""
+	for _, domain := range domainsToCheck {
+		if _, ok := domainsFilterList[domain]; ok {
+			return Block, domain
+		}
+	}
+
+	return NoMatch, ""
 }
 
 // LookupASN checks if the Autonomous system number is in a custom filter list.
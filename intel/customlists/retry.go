@@ -0,0 +1,123 @@
+package customlists
+
+import (
+	"time"
+
+	"github.com/safing/portbase/config"
+)
+
+// retryConfig mirrors the goss/validate retry pattern: attempt an operation
+// up to maxAttempts times, doubling sleep between attempts, and give up once
+// retryTimeout has elapsed since the first attempt - whichever comes first.
+type retryConfig struct {
+	sleep        time.Duration
+	maxAttempts  int
+	retryTimeout time.Duration
+}
+
+// defaultRetryConfig is used for any of "retry-timeout", "sleep" or
+// "max-attempts" that isn't set in the config.
+var defaultRetryConfig = retryConfig{
+	sleep:        2 * time.Second,
+	maxAttempts:  5,
+	retryTimeout: 1 * time.Minute,
+}
+
+const (
+	cfgKeyRetryTimeout     = "intel/customlists/retryTimeout"
+	cfgKeyRetrySleep       = "intel/customlists/retrySleep"
+	cfgKeyMaxRetryAttempts = "intel/customlists/maxRetryAttempts"
+)
+
+var (
+	cfgRetryTimeout     = config.Concurrent.GetAsInt(cfgKeyRetryTimeout, 0)
+	cfgRetrySleep       = config.Concurrent.GetAsInt(cfgKeyRetrySleep, 0)
+	cfgMaxRetryAttempts = config.Concurrent.GetAsInt(cfgKeyMaxRetryAttempts, 0)
+)
+
+// registerRetryConfig registers the config options that allow overriding
+// defaultRetryConfig's sleep, max attempts and overall retry timeout.
+func registerRetryConfig() error {
+	if err := config.Register(&config.Option{
+		Name:         "Filter List Retry Timeout",
+		Key:          cfgKeyRetryTimeout,
+		Description:  "How long, in seconds, to keep retrying a failed filter list source reload before giving up. 0 uses the default.",
+		OptType:      config.OptTypeInt,
+		DefaultValue: 0,
+	}); err != nil {
+		return err
+	}
+
+	if err := config.Register(&config.Option{
+		Name:         "Filter List Retry Sleep",
+		Key:          cfgKeyRetrySleep,
+		Description:  "How long, in seconds, to sleep before the first retry of a failed filter list source reload. Doubles on each subsequent retry. 0 uses the default.",
+		OptType:      config.OptTypeInt,
+		DefaultValue: 0,
+	}); err != nil {
+		return err
+	}
+
+	return config.Register(&config.Option{
+		Name:         "Filter List Max Retry Attempts",
+		Key:          cfgKeyMaxRetryAttempts,
+		Description:  "The maximum number of attempts, counting the first, to reload a filter list source before giving up. 0 uses the default.",
+		OptType:      config.OptTypeInt,
+		DefaultValue: 0,
+	})
+}
+
+// getRetryTimeoutConfig returns the configured retry timeout, or 0 if unset.
+func getRetryTimeoutConfig() time.Duration {
+	return time.Duration(cfgRetryTimeout()) * time.Second
+}
+
+// getRetrySleepConfig returns the configured retry sleep, or 0 if unset.
+func getRetrySleepConfig() time.Duration {
+	return time.Duration(cfgRetrySleep()) * time.Second
+}
+
+// getMaxRetryAttemptsConfig returns the configured max retry attempts, or 0
+// if unset.
+func getMaxRetryAttemptsConfig() int {
+	return cfgMaxRetryAttempts()
+}
+
+// currentRetryConfig returns defaultRetryConfig with any configured
+// overrides applied.
+func currentRetryConfig() retryConfig {
+	cfg := defaultRetryConfig
+	if timeout := getRetryTimeoutConfig(); timeout > 0 {
+		cfg.retryTimeout = timeout
+	}
+	if sleep := getRetrySleepConfig(); sleep > 0 {
+		cfg.sleep = sleep
+	}
+	if attempts := getMaxRetryAttemptsConfig(); attempts > 0 {
+		cfg.maxAttempts = attempts
+	}
+	return cfg
+}
+
+// retry calls fn until it succeeds, cfg.maxAttempts is reached, or
+// cfg.retryTimeout has elapsed since the first attempt. It returns the
+// number of attempts made and the last error, if every attempt failed. This
+// covers transient failures such as a partially-written file caught
+// mid-save, or a 5xx from a remote source.
+func retry(cfg retryConfig, fn func() error) (attempts int, err error) {
+	start := time.Now()
+	sleep := cfg.sleep
+
+	for attempts = 1; attempts <= cfg.maxAttempts; attempts++ {
+		err = fn()
+		if err == nil {
+			return attempts, nil
+		}
+		if attempts == cfg.maxAttempts || time.Since(start) >= cfg.retryTimeout {
+			return attempts, err
+		}
+		time.Sleep(sleep)
+		sleep *= 2
+	}
+	return attempts, err
+}
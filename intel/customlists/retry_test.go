@@ -0,0 +1,83 @@
+package customlists
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetrySucceedsWithoutRetrying(t *testing.T) {
+	calls := 0
+	attempts, err := retry(retryConfig{sleep: time.Millisecond, maxAttempts: 5, retryTimeout: time.Second}, func() error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("retry() error = %v, want nil", err)
+	}
+	if attempts != 1 {
+		t.Errorf("retry() attempts = %d, want 1", attempts)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times, want 1", calls)
+	}
+}
+
+func TestRetryStopsAtMaxAttempts(t *testing.T) {
+	wantErr := errors.New("always fails")
+	calls := 0
+	attempts, err := retry(retryConfig{sleep: time.Millisecond, maxAttempts: 3, retryTimeout: time.Minute}, func() error {
+		calls++
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("retry() error = %v, want %v", err, wantErr)
+	}
+	if attempts != 3 {
+		t.Errorf("retry() attempts = %d, want 3 (maxAttempts)", attempts)
+	}
+	if calls != 3 {
+		t.Errorf("fn called %d times, want 3", calls)
+	}
+}
+
+func TestRetryStopsAtTimeoutBeforeMaxAttempts(t *testing.T) {
+	wantErr := errors.New("always fails")
+	// sleep doubles each attempt (20ms, 40ms, 80ms, ...); a tiny retryTimeout
+	// should cut retries short well before the generous maxAttempts is hit.
+	attempts, err := retry(retryConfig{sleep: 20 * time.Millisecond, maxAttempts: 100, retryTimeout: 25 * time.Millisecond}, func() error {
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("retry() error = %v, want %v", err, wantErr)
+	}
+	if attempts >= 100 {
+		t.Errorf("retry() attempts = %d, want it to stop well before maxAttempts due to retryTimeout", attempts)
+	}
+}
+
+func TestRetrySucceedsAfterTransientFailures(t *testing.T) {
+	calls := 0
+	attempts, err := retry(retryConfig{sleep: time.Millisecond, maxAttempts: 5, retryTimeout: time.Second}, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("retry() error = %v, want nil", err)
+	}
+	if attempts != 3 {
+		t.Errorf("retry() attempts = %d, want 3", attempts)
+	}
+}
+
+func TestCurrentRetryConfigFallsBackToDefaults(t *testing.T) {
+	// Without any config overrides, currentRetryConfig should return exactly
+	// defaultRetryConfig.
+	got := currentRetryConfig()
+	if got != defaultRetryConfig {
+		t.Errorf("currentRetryConfig() = %+v, want defaultRetryConfig %+v", got, defaultRetryConfig)
+	}
+}
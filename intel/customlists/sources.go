@@ -0,0 +1,391 @@
+package customlists
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// urlFetchTimeout bounds how long a single URL source fetch may take, so a
+// hanging remote server can't stall a reload indefinitely.
+const urlFetchTimeout = 30 * time.Second
+
+// Format identifies the syntax a filter list source is written in.
+type Format string
+
+// Supported filter list source formats.
+const (
+	FormatHosts        Format = "hosts"         // /etc/hosts-style "0.0.0.0 example.com" lines.
+	FormatPlainDomains Format = "plain-domains"  // one domain per line.
+	FormatAdblockPlus  Format = "adblock-plus"   // EasyList-style "||example.com^" rules.
+	FormatIPCIDR       Format = "ipcidr"         // one IP or CIDR prefix per line.
+	FormatASN          Format = "asn"            // one "AS<number>" per line.
+	FormatCountry      Format = "country"        // one ISO country code per line.
+
+	// FormatAuto auto-detects each line as a country code, an "AS<number>", a
+	// bare IP/CIDR, or otherwise a plain domain. This is the legacy behavior
+	// of the single local filter list file, kept so existing mixed-type lists
+	// keep working unmodified after multi-source support was added.
+	FormatAuto Format = "auto"
+)
+
+// FilterListSource describes a single input to the custom filter list, be it
+// a local file or a remote URL, and tracks the state needed to reload it
+// independently of the other configured sources.
+type FilterListSource struct {
+	// Path is the local file path of the source. Empty if URL is set.
+	Path string
+	// URL is the remote location of the source. Empty if Path is set.
+	URL string
+	// Format is the syntax the source is written in.
+	Format Format
+
+	// modifiedTime is the last seen modification time of a local file source.
+	modifiedTime time.Time
+	// etag and lastModified are the caching headers of the last successful
+	// fetch of a URL source.
+	etag         string
+	lastModified string
+
+	// entries is the number of entries parsed from this source on its last
+	// successful reload.
+	entries int
+	// parsed is the cached result of the last successful parse, kept so
+	// rebuildFilterLists can recompute the merged lookup structures without
+	// re-fetching or re-parsing sources that haven't changed.
+	parsed sourceEntries
+	// lastErr is the error from the last reload attempt, if any. On failure
+	// the previously-loaded entries in parsed are left untouched, so a
+	// source keeps serving its last good version until a reload succeeds.
+	lastErr error
+	// retryAttempts is how many attempts the last reload took, whether it
+	// ultimately succeeded or failed.
+	retryAttempts int
+	// lastUpdate is when this source was last successfully reloaded.
+	lastUpdate time.Time
+}
+
+// sourceEntries holds the block- and allow-listed entries parsed from a
+// single source, split out by type.
+type sourceEntries struct {
+	domains      []string
+	allowDomains []string
+	ips          []netip.Prefix
+	allowIPs     []netip.Prefix
+	asns         []uint
+	countries    []string
+}
+
+func (e sourceEntries) count() int {
+	return len(e.domains) + len(e.allowDomains) + len(e.ips) + len(e.allowIPs) + len(e.asns) + len(e.countries)
+}
+
+// String returns the source's origin, for logging.
+func (s *FilterListSource) String() string {
+	if s.URL != "" {
+		return s.URL
+	}
+	return s.Path
+}
+
+// fetch returns the current contents of the source along with whether the
+// content has changed since the last successful reload. For local files this
+// is based on the modification time, for URL sources on ETag/Last-Modified.
+func (s *FilterListSource) fetch() (content io.ReadCloser, changed bool, err error) {
+	if s.URL != "" {
+		return s.fetchURL()
+	}
+	return s.fetchFile()
+}
+
+func (s *FilterListSource) fetchFile() (io.ReadCloser, bool, error) {
+	fileInfo, err := os.Stat(s.Path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to stat %s: %w", s.Path, err)
+	}
+
+	if s.modifiedTime.Equal(fileInfo.ModTime()) {
+		return nil, false, nil
+	}
+
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open %s: %w", s.Path, err)
+	}
+
+	s.modifiedTime = fileInfo.ModTime()
+	return file, true, nil
+}
+
+func (s *FilterListSource) fetchURL() (io.ReadCloser, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), urlFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to build request for %s: %w", s.URL, err)
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	if s.lastModified != "" {
+		req.Header.Set("If-Modified-Since", s.lastModified)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch %s: %w", s.URL, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		_ = resp.Body.Close()
+		return nil, false, nil
+	case http.StatusOK:
+		// Buffer the body so it can be read after fetchURL's context is
+		// canceled by the deferred cancel() above.
+		buf, readErr := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if readErr != nil {
+			return nil, false, fmt.Errorf("failed to read response body from %s: %w", s.URL, readErr)
+		}
+		s.etag = resp.Header.Get("ETag")
+		s.lastModified = resp.Header.Get("Last-Modified")
+		return io.NopCloser(bytes.NewReader(buf)), true, nil
+	default:
+		_ = resp.Body.Close()
+		return nil, false, fmt.Errorf("unexpected status %s fetching %s", resp.Status, s.URL)
+	}
+}
+
+// lineError associates a parsing error with the 1-indexed source line it
+// occurred on, so callers can report which line of a filter list is at
+// fault.
+type lineError struct {
+	line int
+	err  error
+}
+
+func (e *lineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.err)
+}
+
+func (e *lineError) Unwrap() error {
+	return e.err
+}
+
+// parseSourceContent parses content according to format into a sourceEntries,
+// splitting block- from allow-rules. A line is treated as an allow rule if
+// it is prefixed with "@@" (adblock-plus sources) or "!" (all other
+// formats), mirroring ABP exception syntax. Allow rules apply to domain and
+// IP entries only; an allow-marked ASN or country line is skipped. Errors
+// are returned as a *lineError identifying the offending line.
+func parseSourceContent(content io.Reader, format Format) (sourceEntries, error) {
+	var parsed sourceEntries
+
+	lineNum := 0
+	scanner := bufio.NewScanner(content)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		value, allow := parseAllowRule(line, format)
+
+		switch format {
+		case FormatHosts:
+			fields := strings.Fields(value)
+			if len(fields) < 2 {
+				continue
+			}
+			appendDomain(&parsed, fields[1], allow)
+
+		case FormatPlainDomains:
+			appendDomain(&parsed, value, allow)
+
+		case FormatAdblockPlus:
+			domain := strings.TrimSuffix(strings.TrimPrefix(value, "||"), "^")
+			if domain == value {
+				// Not an anchored domain rule, skip it.
+				continue
+			}
+			appendDomain(&parsed, domain, allow)
+
+		case FormatIPCIDR:
+			prefix, prefixErr := parsePrefix(value)
+			if prefixErr != nil {
+				continue
+			}
+			if allow {
+				parsed.allowIPs = append(parsed.allowIPs, prefix)
+			} else {
+				parsed.ips = append(parsed.ips, prefix)
+			}
+
+		case FormatASN:
+			if allow || !isAutonomousSystem(value) {
+				continue
+			}
+			number, convErr := strconv.ParseUint(strings.TrimPrefix(value, "AS"), 10, 32)
+			if convErr != nil {
+				continue
+			}
+			parsed.asns = append(parsed.asns, uint(number))
+
+		case FormatCountry:
+			if allow || !isCountryCode(value) {
+				continue
+			}
+			parsed.countries = append(parsed.countries, value)
+
+		case FormatAuto:
+			switch {
+			case isCountryCode(value):
+				if allow {
+					continue
+				}
+				parsed.countries = append(parsed.countries, value)
+
+			case isAutonomousSystem(value):
+				if allow {
+					continue
+				}
+				number, convErr := strconv.ParseUint(strings.TrimPrefix(value, "AS"), 10, 32)
+				if convErr != nil {
+					continue
+				}
+				parsed.asns = append(parsed.asns, uint(number))
+
+			default:
+				if prefix, prefixErr := parsePrefix(value); prefixErr == nil {
+					if allow {
+						parsed.allowIPs = append(parsed.allowIPs, prefix)
+					} else {
+						parsed.ips = append(parsed.ips, prefix)
+					}
+				} else {
+					appendDomain(&parsed, value, allow)
+				}
+			}
+
+		default:
+			return parsed, &lineError{line: lineNum, err: fmt.Errorf("unknown filter list format %q", format)}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return parsed, &lineError{line: lineNum, err: err}
+	}
+	return parsed, nil
+}
+
+// parseAllowRule strips a source's allow-rule marker from line, if present,
+// and reports whether it was an allow rule.
+func parseAllowRule(line string, format Format) (value string, allow bool) {
+	if format == FormatAdblockPlus {
+		if rest, ok := strings.CutPrefix(line, "@@"); ok {
+			return rest, true
+		}
+		return line, false
+	}
+
+	if rest, ok := strings.CutPrefix(line, "!"); ok {
+		return rest, true
+	}
+	return line, false
+}
+
+func appendDomain(parsed *sourceEntries, domain string, allow bool) {
+	if allow {
+		parsed.allowDomains = append(parsed.allowDomains, domain)
+	} else {
+		parsed.domains = append(parsed.domains, domain)
+	}
+}
+
+// parsePrefix parses value as a CIDR range, or as a bare IP address treated
+// as a single-address /32 or /128 host route.
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		return netip.ParsePrefix(value)
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// URLSourceConfig describes a single remote filter list source, as parsed
+// from the "Custom Filter List URL Sources" config option.
+type URLSourceConfig struct {
+	URL    string
+	Format Format
+}
+
+// loadConfiguredSources builds the set of filter list sources from the
+// current config: the legacy single local file option, plus any configured
+// remote URL sources. State (modtime, ETag, last result) is not carried over
+// here; callers should use syncConfiguredSources to merge it in.
+func loadConfiguredSources() []*FilterListSource {
+	sources := make([]*FilterListSource, 0, 1)
+
+	if filePath := getFilePath(); filePath != "" {
+		sources = append(sources, &FilterListSource{
+			Path:   filePath,
+			Format: FormatAuto,
+		})
+	}
+
+	for _, urlSource := range getURLSources() {
+		sources = append(sources, &FilterListSource{
+			URL:    urlSource.URL,
+			Format: urlSource.Format,
+		})
+	}
+
+	return sources
+}
+
+// Status is the externally-reported state of a single filter list source,
+// served via the customlists/status API endpoint.
+type Status struct {
+	Source        string `json:"source"`
+	Format        Format `json:"format"`
+	Entries       int    `json:"entries"`
+	RetryAttempts int    `json:"retry_attempts,omitempty"`
+	LastUpdate    int64  `json:"last_update,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
+}
+
+// getSourcesStatus returns the current status of all configured filter list
+// sources. Callers must hold filterListLock.
+func getSourcesStatus() []Status {
+	status := make([]Status, 0, len(filterListSources))
+	for _, source := range filterListSources {
+		s := Status{
+			Source:        source.String(),
+			Format:        source.Format,
+			Entries:       source.entries,
+			RetryAttempts: source.retryAttempts,
+		}
+		if !source.lastUpdate.IsZero() {
+			s.LastUpdate = source.lastUpdate.Unix()
+		}
+		if source.lastErr != nil {
+			s.LastError = source.lastErr.Error()
+		}
+		status = append(status, s)
+	}
+	return status
+}
@@ -0,0 +1,54 @@
+package customlists
+
+import (
+	"strings"
+
+	"github.com/safing/portbase/config"
+
+	"github.com/safing/portmaster/logging"
+)
+
+// cfgKeyURLSources is the config key for the URL sources option registered
+// below.
+const cfgKeyURLSources = "intel/customlists/urlSources"
+
+var cfgURLSources = config.Concurrent.GetAsStringArray(cfgKeyURLSources, []string{})
+
+// registerSourceConfig registers the config option backing URL-based filter
+// list sources, in addition to the legacy single-file option registered by
+// registerConfig.
+func registerSourceConfig() error {
+	return config.Register(&config.Option{
+		Name: "Custom Filter List URL Sources",
+		Key:  cfgKeyURLSources,
+		Description: `Additional remote filter lists to merge in, one entry per source, ` +
+			`formatted as "<format>|<url>", eg. "adblock-plus|https://example.com/list.txt". ` +
+			`Supported formats: hosts, plain-domains, adblock-plus, ipcidr, asn, country.`,
+		OptType:      config.OptTypeStringArray,
+		DefaultValue: []string{},
+	})
+}
+
+// getURLSources parses the configured URL source list into URLSourceConfig
+// entries. A malformed entry (missing "|") is logged and skipped rather than
+// failing the whole reload - a config typo shouldn't take down every other
+// configured source.
+func getURLSources() []URLSourceConfig {
+	raw := cfgURLSources()
+	urlSources := make([]URLSourceConfig, 0, len(raw))
+
+	for _, entry := range raw {
+		format, url, ok := strings.Cut(entry, "|")
+		if !ok {
+			logger.Warn("filter_list_source_config_invalid", logging.F("entry", entry))
+			continue
+		}
+
+		urlSources = append(urlSources, URLSourceConfig{
+			URL:    url,
+			Format: Format(format),
+		})
+	}
+
+	return urlSources
+}
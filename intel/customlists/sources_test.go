@@ -0,0 +1,200 @@
+package customlists
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSourceContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		content string
+		want    sourceEntries
+	}{
+		{
+			name:    "hosts block and allow",
+			format:  FormatHosts,
+			content: "0.0.0.0 block.example\n!0.0.0.0 allow.example\n# comment\n\nnotenoughfields\n",
+			want: sourceEntries{
+				domains:      []string{"block.example"},
+				allowDomains: []string{"allow.example"},
+			},
+		},
+		{
+			name:    "plain domains block and allow",
+			format:  FormatPlainDomains,
+			content: "block.example\n!allow.example\n",
+			want: sourceEntries{
+				domains:      []string{"block.example"},
+				allowDomains: []string{"allow.example"},
+			},
+		},
+		{
+			name:    "adblock plus block and allow",
+			format:  FormatAdblockPlus,
+			content: "||block.example^\n@@||allow.example^\nnot-an-anchor-rule\n",
+			want: sourceEntries{
+				domains:      []string{"block.example"},
+				allowDomains: []string{"allow.example"},
+			},
+		},
+		{
+			name:    "ip cidr block and allow",
+			format:  FormatIPCIDR,
+			content: "10.0.0.1\n!10.0.0.0/24\ninvalid\n",
+			want: sourceEntries{
+				ips:      []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")},
+				allowIPs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")},
+			},
+		},
+		{
+			name:    "asn",
+			format:  FormatASN,
+			content: "AS1234\nnotanasn\n",
+			want: sourceEntries{
+				asns: []uint{1234},
+			},
+		},
+		{
+			name:    "country",
+			format:  FormatCountry,
+			content: "US\nnotacountry\n",
+			want: sourceEntries{
+				countries: []string{"US"},
+			},
+		},
+		{
+			name:    "auto detects country, asn, ip, and falls back to domain",
+			format:  FormatAuto,
+			content: "US\nAS1234\n10.0.0.1\n!10.0.0.2\nexample.com\n!allow.example.com\n",
+			want: sourceEntries{
+				domains:      []string{"example.com"},
+				allowDomains: []string{"allow.example.com"},
+				ips:          []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")},
+				allowIPs:     []netip.Prefix{netip.MustParsePrefix("10.0.0.2/32")},
+				asns:         []uint{1234},
+				countries:    []string{"US"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSourceContent(strings.NewReader(tt.content), tt.format)
+			if err != nil {
+				t.Fatalf("parseSourceContent() error = %v", err)
+			}
+			if !entriesEqual(got, tt.want) {
+				t.Errorf("parseSourceContent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSourceContentUnknownFormatReportsLine(t *testing.T) {
+	_, err := parseSourceContent(strings.NewReader("first\nsecond\n"), Format("bogus"))
+	if err == nil {
+		t.Fatal("parseSourceContent() error = nil, want error for unknown format")
+	}
+
+	var le *lineError
+	if !errors.As(err, &le) {
+		t.Fatalf("parseSourceContent() error = %v, want *lineError", err)
+	}
+	if le.line != 1 {
+		t.Errorf("lineError.line = %d, want 1 (fails on the first line scanned)", le.line)
+	}
+}
+
+func entriesEqual(a, b sourceEntries) bool {
+	return stringsEqual(a.domains, b.domains) &&
+		stringsEqual(a.allowDomains, b.allowDomains) &&
+		prefixesEqual(a.ips, b.ips) &&
+		prefixesEqual(a.allowIPs, b.allowIPs) &&
+		uintsEqual(a.asns, b.asns) &&
+		stringsEqual(a.countries, b.countries)
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func uintsEqual(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func prefixesEqual(a, b []netip.Prefix) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMergeSourcesCarriesOverCachedState(t *testing.T) {
+	lastUpdate := time.Now().Add(-time.Hour)
+	existing := []*FilterListSource{
+		{
+			Path:         "/etc/portmaster/list.txt",
+			Format:       FormatAuto,
+			modifiedTime: lastUpdate,
+			etag:         "unused-for-file-sources",
+			entries:      42,
+			lastErr:      errors.New("previous failure"),
+			lastUpdate:   lastUpdate,
+		},
+		{
+			URL:          "https://example.com/removed.txt",
+			Format:       FormatPlainDomains,
+			etag:         "should-not-carry-over",
+			entries:      7,
+		},
+	}
+
+	// The local file is still configured (unchanged); the URL source has
+	// been removed from config and a brand new URL source has been added.
+	configured := []*FilterListSource{
+		{Path: "/etc/portmaster/list.txt", Format: FormatAuto},
+		{URL: "https://example.com/new.txt", Format: FormatIPCIDR},
+	}
+
+	merged := mergeSources(configured, existing)
+
+	if len(merged) != 2 {
+		t.Fatalf("mergeSources() returned %d sources, want 2", len(merged))
+	}
+
+	file := merged[0]
+	if file.modifiedTime != lastUpdate || file.entries != 42 || file.lastErr == nil || file.lastUpdate != lastUpdate {
+		t.Errorf("mergeSources() did not carry over cached state for the still-configured file source: %+v", file)
+	}
+
+	newURL := merged[1]
+	if newURL.etag != "" || newURL.entries != 0 || newURL.lastErr != nil {
+		t.Errorf("mergeSources() carried over state onto an unrelated new source: %+v", newURL)
+	}
+}
@@ -0,0 +1,98 @@
+// Package logging provides a small structured-logging façade on top of
+// portbase/log. Instead of interpolating values into free-form strings,
+// callers attach typed key/value fields to a named event on a per-subsystem
+// logger, similar to hclog. This keeps log lines machine-parsable while
+// still routing through the existing portbase log levels and output.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/safing/portbase/log"
+)
+
+// Field is a single structured key/value pair attached to a log event.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F is a shorthand constructor for Field, eg. logging.F("pid", 1234).
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger emits structured events for a single named subsystem, eg. "process"
+// or "intel/customlists".
+type Logger struct {
+	subsystem string
+}
+
+// New returns a Logger for the given subsystem name.
+func New(subsystem string) *Logger {
+	return &Logger{subsystem: subsystem}
+}
+
+// With returns a copy of the logger scoped to a nested subsystem name, eg.
+// New("intel/customlists").With("source") logs as "intel/customlists/source".
+func (l *Logger) With(subsystem string) *Logger {
+	return &Logger{subsystem: l.subsystem + "/" + subsystem}
+}
+
+func (l *Logger) format(event string, fields []Field) string {
+	var b strings.Builder
+	b.WriteString(l.subsystem)
+	b.WriteString(": ")
+	b.WriteString(event)
+	for _, f := range fields {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		fmt.Fprintf(&b, "%v", f.Value)
+	}
+	return b.String()
+}
+
+// Debug logs a structured debug-level event.
+func (l *Logger) Debug(event string, fields ...Field) {
+	log.Debugf("%s", l.format(event, fields))
+}
+
+// Info logs a structured info-level event.
+func (l *Logger) Info(event string, fields ...Field) {
+	log.Infof("%s", l.format(event, fields))
+}
+
+// Warn logs a structured warning-level event.
+func (l *Logger) Warn(event string, fields ...Field) {
+	log.Warningf("%s", l.format(event, fields))
+}
+
+// Error logs a structured error-level event.
+func (l *Logger) Error(event string, fields ...Field) {
+	log.Errorf("%s", l.format(event, fields))
+}
+
+// DebugCtx logs a structured debug-level event attached to ctx's trace log,
+// instead of the global subsystem log, so it surfaces in portbase's
+// per-request trace alongside everything else that request did.
+func (l *Logger) DebugCtx(ctx context.Context, event string, fields ...Field) {
+	log.Tracer(ctx).Debugf("%s", l.format(event, fields))
+}
+
+// InfoCtx logs a structured info-level event attached to ctx's trace log.
+func (l *Logger) InfoCtx(ctx context.Context, event string, fields ...Field) {
+	log.Tracer(ctx).Infof("%s", l.format(event, fields))
+}
+
+// WarnCtx logs a structured warning-level event attached to ctx's trace log.
+func (l *Logger) WarnCtx(ctx context.Context, event string, fields ...Field) {
+	log.Tracer(ctx).Warningf("%s", l.format(event, fields))
+}
+
+// ErrorCtx logs a structured error-level event attached to ctx's trace log.
+func (l *Logger) ErrorCtx(ctx context.Context, event string, fields ...Field) {
+	log.Tracer(ctx).Errorf("%s", l.format(event, fields))
+}
@@ -0,0 +1,92 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/safing/portmaster/logging"
+)
+
+// EventType identifies what changed in an Event reported by a
+// ProcessSource's Watch channel.
+type EventType int
+
+const (
+	// EventStarted is reported when a source observes a new process.
+	EventStarted EventType = iota
+	// EventExited is reported when a source observes a process exiting.
+	EventExited
+)
+
+// Event describes a process lifecycle change reported by a ProcessSource.
+type Event struct {
+	Type EventType
+	Pid  int
+}
+
+// ProcessSource discovers and enriches processes. Portmaster consults an
+// ordered chain of sources - built-in OS backends first, then any
+// registered plugins - so that, eg. a container-runtime plugin can annotate
+// processes with pod/container metadata without Portmaster needing to know
+// about it at compile time.
+type ProcessSource interface {
+	// Name identifies the source, for logging.
+	Name() string
+	// Lookup returns the process for pid, as known to this source.
+	Lookup(pid int) (*Process, error)
+	// Enrich adds source-specific metadata to an already-resolved process.
+	Enrich(process *Process) error
+	// Watch streams process lifecycle events until ctx is canceled.
+	Watch(ctx context.Context) <-chan Event
+}
+
+var (
+	sourcesLock sync.RWMutex
+	sources     []ProcessSource
+)
+
+// RegisterSource appends a ProcessSource to the end of the lookup chain.
+// Sources are consulted in registration order, so built-in OS sources
+// should register before any plugins are loaded.
+func RegisterSource(source ProcessSource) {
+	sourcesLock.Lock()
+	defer sourcesLock.Unlock()
+
+	sources = append(sources, source)
+	logger.Info("process_source_registered", logging.F("source", source.Name()))
+}
+
+// lookupViaSources consults the registered source chain for pid, returning
+// the first source's successful result.
+func lookupViaSources(pid int) (*Process, error) {
+	sourcesLock.RLock()
+	defer sourcesLock.RUnlock()
+
+	for _, source := range sources {
+		found, err := source.Lookup(pid)
+		if err != nil || found == nil {
+			continue
+		}
+		return found, nil
+	}
+	return nil, errors.New("process: no registered source resolved pid")
+}
+
+// enrichViaSources runs every registered source's Enrich over process. A
+// source's error is logged and otherwise ignored, so one misbehaving source
+// can't block attribution by the others.
+func enrichViaSources(process *Process) {
+	sourcesLock.RLock()
+	defer sourcesLock.RUnlock()
+
+	for _, source := range sources {
+		if err := source.Enrich(process); err != nil {
+			logger.Warn("process_source_enrich_failed",
+				logging.F("source", source.Name()),
+				logging.F("pid", process.Pid),
+				logging.F("err", err),
+			)
+		}
+	}
+}
@@ -2,11 +2,14 @@ package process
 
 import (
 	"context"
+	"net"
 	"strconv"
 	"time"
 
-	"github.com/safing/portbase/log"
 	"golang.org/x/sync/singleflight"
+
+	"github.com/safing/portmaster/dnssniffer"
+	"github.com/safing/portmaster/logging"
 )
 
 // UnidentifiedProcessID is the PID used for anything that could not be
@@ -33,6 +36,8 @@ var (
 	}
 
 	getSpecialProcessSingleInflight singleflight.Group
+
+	logger = logging.New("process")
 )
 
 // GetUnidentifiedProcess returns the special process assigned to unidentified processes.
@@ -45,6 +50,33 @@ func GetSystemProcess(ctx context.Context) *Process {
 	return getSpecialProcess(ctx, systemProcess)
 }
 
+// GetUnidentifiedProcessForConnection returns the special process assigned to
+// unidentified processes, unless the dnssniffer subsystem recently observed
+// a DNS query for remoteIP, in which case the connection is attributed to
+// the querying process instead. This catches short-lived processes that
+// have already exited by the time the connection is inspected.
+//
+// STATUS: not wired up. Nothing in this tree calls this function, and
+// dnssniffer has no capture backend to ever populate LookupRecentDomain
+// (see the dnssniffer package doc) - so today this always falls through to
+// GetUnidentifiedProcess. This is tracked as a follow-up, not a finished
+// feature: it still needs a real capture backend and a call site on the
+// per-connection process resolution path.
+func GetUnidentifiedProcessForConnection(ctx context.Context, remoteIP net.IP) *Process {
+	if domain, pid, ok := dnssniffer.LookupRecentDomain(remoteIP); ok {
+		if process, ok := GetProcessFromStorage(pid); ok {
+			logger.Info("special_process_attributed_via_dns",
+				logging.F("pid", pid),
+				logging.F("domain", domain),
+				logging.F("ip", remoteIP),
+			)
+			return process
+		}
+	}
+
+	return GetUnidentifiedProcess(ctx)
+}
+
 func getSpecialProcess(ctx context.Context, template *Process) *Process {
 	p, _, _ := getSpecialProcessSingleInflight.Do(strconv.Itoa(template.Pid), func() (interface{}, error) {
 		// Check if we have already loaded the special process.
@@ -53,14 +85,24 @@ func getSpecialProcess(ctx context.Context, template *Process) *Process {
 			return process, nil
 		}
 
-		// Create new process from template
-		process = template
+		// Consult the registered process source chain first, so a plugin can
+		// supply a richer definition for this special process. Fall back to
+		// the static template if none do.
+		if found, err := lookupViaSources(template.Pid); err == nil && found != nil {
+			process = found
+		} else {
+			process = template
+		}
 		process.FirstSeen = time.Now().Unix()
+		enrichViaSources(process)
 
 		// Get profile.
 		_, err := process.GetProfile(ctx)
 		if err != nil {
-			log.Tracer(ctx).Errorf("process: failed to get profile for process %s: %s", process, err)
+			logger.ErrorCtx(ctx, "special_process_profile_failed",
+				logging.F("pid", process.Pid),
+				logging.F("err", err),
+			)
 		}
 
 		// Save process to storage.